@@ -0,0 +1,205 @@
+package gostream
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+// BitrateLayer describes one pre-encoded quality variant of the same source
+// image.Image stream. TargetKBPS is the layer's target bitrate in kbps, used
+// as the threshold watchBitrateEstimate switches clients on; it is not passed
+// to the encoder. EncoderFactory builds the Encoder that produces the layer
+// and alone decides how it is encoded.
+type BitrateLayer struct {
+	Name           string
+	TargetKBPS     int
+	EncoderFactory EncoderFactory
+}
+
+// bitrateTrack pairs a BitrateLayer with the shared track its encoder goroutine
+// writes samples to; the same track is bound to every client currently switched
+// to that layer via RTPSender.ReplaceTrack.
+type bitrateTrack struct {
+	layer  BitrateLayer
+	track  *webrtc.TrackLocalStaticSample
+	frames chan image.Image
+}
+
+// SetBitrateLayers configures the quality layers clients can be adaptively
+// switched between, and starts one encoder goroutine per layer fed from the
+// same inputFrames stream via fan-out, similar to neko's changeVideo(bitrate
+// int) track-switching. Layers must be supplied lowest-to-highest TargetKBPS;
+// watchBitrateEstimate relies on that ordering to pick a layer for a given
+// estimated bitrate. It may only be called once.
+//
+// watchBitrateEstimate moves a connected client between layers with
+// ReplaceTrack, which doesn't renegotiate SDP, so every layer must advertise
+// the same codec as the one originally negotiated for that client; all
+// layers are therefore required to share one MIME type.
+func (brv *basicRemoteView) SetBitrateLayers(layers []BitrateLayer) error {
+	if len(layers) == 0 {
+		return errors.New("at least one bitrate layer required")
+	}
+	for _, layer := range layers[1:] {
+		if layer.EncoderFactory.MIMEType() != layers[0].EncoderFactory.MIMEType() {
+			return fmt.Errorf("bitrate layer %q uses MIME type %q, want %q like layer %q",
+				layer.Name, layer.EncoderFactory.MIMEType(), layers[0].EncoderFactory.MIMEType(), layers[0].Name)
+		}
+	}
+
+	brv.mu.Lock()
+	if brv.bitrateTracks != nil {
+		brv.mu.Unlock()
+		return errors.New("bitrate layers already set")
+	}
+
+	tracks := make([]bitrateTrack, 0, len(layers))
+	for _, layer := range layers {
+		track, err := webrtc.NewTrackLocalStaticSample(
+			webrtc.RTPCodecCapability{MimeType: layer.EncoderFactory.MIMEType()},
+			"video",
+			"pion",
+		)
+		if err != nil {
+			brv.mu.Unlock()
+			return err
+		}
+		tracks = append(tracks, bitrateTrack{layer: layer, track: track, frames: make(chan image.Image, 1)})
+	}
+	brv.bitrateTracks = tracks
+	if brv.layerEncoders == nil {
+		brv.layerEncoders = map[string]Encoder{}
+	}
+	brv.mu.Unlock()
+
+	for _, bt := range tracks {
+		brv.backgroundProcessing.Add(1)
+		go brv.processLayer(bt)
+	}
+	return nil
+}
+
+// fanOutToLayers forwards frame to every configured bitrate layer's encoder
+// goroutine, dropping it for a layer that's still busy with the previous frame
+// rather than blocking the shared input pipeline.
+func (brv *basicRemoteView) fanOutToLayers(frame image.Image) {
+	brv.mu.Lock()
+	tracks := brv.bitrateTracks
+	brv.mu.Unlock()
+	for _, bt := range tracks {
+		select {
+		case bt.frames <- frame:
+		default:
+		}
+	}
+}
+
+func (brv *basicRemoteView) processLayer(bt bitrateTrack) {
+	defer brv.backgroundProcessing.Done()
+	var encoder Encoder
+	for {
+		select {
+		case <-brv.shutdownCtx.Done():
+			return
+		case frame, ok := <-bt.frames:
+			if !ok {
+				return
+			}
+			if frame == nil {
+				continue
+			}
+			if encoder == nil {
+				bounds := frame.Bounds()
+				var err error
+				encoder, err = bt.layer.EncoderFactory.New(bounds.Dx(), bounds.Dy(), brv.logger)
+				if err != nil {
+					brv.logger.Error(err)
+					continue
+				}
+				brv.mu.Lock()
+				brv.layerEncoders[bt.layer.Name] = encoder
+				brv.mu.Unlock()
+			}
+			data, err := encoder.Encode(frame)
+			if err != nil {
+				brv.logger.Error(err)
+				continue
+			}
+			if data == nil {
+				continue
+			}
+			if err := bt.track.WriteSample(media.Sample{Data: data, Duration: 33 * time.Millisecond}); err != nil {
+				brv.logger.Error(err)
+			}
+		}
+	}
+}
+
+// forceKeyFrameForLayer requests a keyframe from the layer named layerName's
+// encoder, if one has been created yet. Every client currently switched to
+// that layer shares the same encoder and track, so this covers all of them.
+func (brv *basicRemoteView) forceKeyFrameForLayer(layerName string) {
+	brv.mu.Lock()
+	encoder, ok := brv.layerEncoders[layerName]
+	brv.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err := encoder.ForceKeyFrame(); err != nil {
+		brv.logger.Error(err)
+	}
+}
+
+// watchBitrateEstimate switches peerConnection's client to the highest
+// configured layer whose TargetKBPS fits within estimator's reported downlink
+// bitrate, for as long as brv runs. It's a no-op when SetBitrateLayers hasn't
+// been called.
+func (brv *basicRemoteView) watchBitrateEstimate(peerConnection *webrtc.PeerConnection, estimator cc.BandwidthEstimator) {
+	if estimator == nil {
+		return
+	}
+	estimator.OnTargetBitrateChange(func(bitrateBPS int) {
+		brv.mu.Lock()
+		tracks := brv.bitrateTracks
+		brv.mu.Unlock()
+		if len(tracks) == 0 {
+			return
+		}
+
+		target := tracks[0]
+		for _, bt := range tracks {
+			if bt.layer.TargetKBPS*1000 <= bitrateBPS {
+				target = bt
+			}
+		}
+
+		brv.mu.Lock()
+		rc, ok := brv.peerToRemoteClient[peerConnection]
+		brv.mu.Unlock()
+		if !ok || rc.videoSender == nil {
+			return
+		}
+		if rc.layer != nil && rc.layer.Name == target.layer.Name {
+			return
+		}
+
+		if err := rc.videoSender.ReplaceTrack(target.track); err != nil {
+			brv.logger.Error(err)
+			return
+		}
+		rc.videoTrack = target.track
+		rc.layer = &target.layer
+		brv.updateRemoteClient(peerConnection, rc)
+		// The viewer is now decoding target's track from whatever point its encoder
+		// goroutine happens to be at, not a clean start; force the layer's shared
+		// encoder to produce an IDR so it doesn't stare at a gray screen until the
+		// next natural keyframe.
+		brv.forceKeyFrameForLayer(target.layer.Name)
+	})
+}
@@ -0,0 +1,22 @@
+package gostream
+
+import (
+	"github.com/edaniels/golog"
+	"github.com/pion/webrtc/v3"
+)
+
+// RemoteViewConfig configures a RemoteView.
+type RemoteViewConfig struct {
+	StreamNumber int
+	StreamName   string
+	// EncoderFactories lists the codecs this view can advertise, in preference
+	// order. Handler picks the first one also offered by the connecting peer's SDP.
+	EncoderFactories []EncoderFactory
+	// Depacketizers, keyed by lowercased RTP MIME type (e.g. "video/h264"),
+	// enables receiving inbound video from the connecting peer. When empty,
+	// RemoteView stays send-only as before.
+	Depacketizers map[string]Depacketizer
+	WebRTCConfig  webrtc.Configuration
+	Logger        golog.Logger
+	Debug         bool
+}
@@ -0,0 +1,23 @@
+package gostream
+
+import (
+	"image"
+
+	"github.com/edaniels/golog"
+)
+
+// Encoder turns raw frames into encoded samples for a single negotiated codec.
+type Encoder interface {
+	Encode(img image.Image) ([]byte, error)
+	// ForceKeyFrame requests that the next Encode call produce a keyframe, so a
+	// viewer that just joined or missed packets to a NACK doesn't wait for the
+	// encoder's natural keyframe interval.
+	ForceKeyFrame() error
+}
+
+// EncoderFactory builds an Encoder for a specific codec once frame dimensions are
+// known, and reports the MIME type Handler should advertise for it.
+type EncoderFactory interface {
+	New(width, height int, logger golog.Logger) (Encoder, error)
+	MIMEType() string
+}
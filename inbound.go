@@ -0,0 +1,73 @@
+package gostream
+
+import (
+	"image"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Depacketizer turns one inbound RTP video track's payloads into decoded
+// frames. Implementations exist per codec (H264, VP8, VP9); RemoteViewConfig
+// selects one by the track's negotiated MIME type.
+type Depacketizer interface {
+	// Unmarshal extracts the codec payload from a single RTP packet's payload,
+	// returning nil if the packet didn't complete an access unit.
+	Unmarshal(rtpPayload []byte) ([]byte, error)
+	// Decode turns an access unit produced by Unmarshal into an image.
+	Decode(data []byte) (image.Image, error)
+}
+
+// watchInboundTracks registers peerConnection.OnTrack so that, when the
+// connecting peer offers to send video (webcam, screen share), frames are
+// depacketized, decoded, and published on brv.recvFrames for OutputFrames
+// subscribers. It's a no-op for peers that only receive.
+func (brv *basicRemoteView) watchInboundTracks(peerConnection *webrtc.PeerConnection) {
+	peerConnection.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		if track.Kind() != webrtc.RTPCodecTypeVideo {
+			return
+		}
+		depacketizer, ok := brv.config.Depacketizers[strings.ToLower(track.Codec().MimeType)]
+		if !ok {
+			brv.logger.Debugw("no depacketizer for inbound track codec", "mime_type", track.Codec().MimeType)
+			return
+		}
+
+		brv.backgroundProcessing.Add(1)
+		go func() {
+			defer brv.backgroundProcessing.Done()
+			for {
+				select {
+				case <-brv.shutdownCtx.Done():
+					return
+				default:
+				}
+				packet, _, err := track.ReadRTP()
+				if err != nil {
+					return
+				}
+				payload, err := depacketizer.Unmarshal(packet.Payload)
+				if err != nil {
+					brv.logger.Error(err)
+					continue
+				}
+				if payload == nil {
+					continue
+				}
+				frame, err := depacketizer.Decode(payload)
+				if err != nil {
+					brv.logger.Error(err)
+					continue
+				}
+				if frame == nil {
+					continue
+				}
+				select {
+				case brv.recvFrames <- frame:
+				case <-brv.shutdownCtx.Done():
+					return
+				}
+			}
+		}()
+	})
+}
@@ -0,0 +1,101 @@
+package gostream
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/trevor403/gostream/pkg/input"
+)
+
+// InputHandler receives structured input events decoded from a client's
+// InputChannel messages. Implementations typically forward them to an OS
+// input-injection backend; pkg/input/direct provides one. A RemoteView has no
+// InputHandler until SetInputHandler is called, and drops InputChannel
+// messages until then.
+type InputHandler interface {
+	HandleKey(ev input.KeyEvent) error
+	HandleMouse(ev input.MouseEvent) error
+	HandleWheel(ev input.WheelEvent) error
+	HandleTouch(ev input.TouchEvent) error
+	HandleGamepad(ev input.GamepadEvent) error
+	HandleClipboard(ev input.ClipboardEvent) error
+}
+
+// dispatchInputEvent decodes one InputChannel message and routes it to the
+// matching InputHandler method. It's called with brv.inputHandler already
+// known non-nil.
+func (brv *basicRemoteView) dispatchInputEvent(data []byte) error {
+	var raw input.RawEvent
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Version != input.Schema {
+		return fmt.Errorf("unsupported input event schema version %d (want %d)", raw.Version, input.Schema)
+	}
+
+	switch raw.Type {
+	case input.KeyEventType:
+		var ev input.KeyEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return err
+		}
+		return brv.inputHandler.HandleKey(ev)
+	case input.MouseEventType:
+		var ev input.MouseEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return err
+		}
+		return brv.inputHandler.HandleMouse(ev)
+	case input.WheelEventType:
+		var ev input.WheelEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return err
+		}
+		return brv.inputHandler.HandleWheel(ev)
+	case input.TouchEventType:
+		var ev input.TouchEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return err
+		}
+		return brv.inputHandler.HandleTouch(ev)
+	case input.GamepadEventType:
+		var ev input.GamepadEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return err
+		}
+		return brv.inputHandler.HandleGamepad(ev)
+	case input.ClipboardEventType:
+		var ev input.ClipboardEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return err
+		}
+		return brv.inputHandler.HandleClipboard(ev)
+	}
+	return nil
+}
+
+// createInputChannel creates the "input" data channel that replaces the
+// old ad-hoc "clicks" channel, decoding every message as a versioned
+// input.RawEvent and dispatching it to the configured InputHandler.
+func (brv *basicRemoteView) createInputChannel(peerConnection *webrtc.PeerConnection) (*webrtc.DataChannel, error) {
+	inputChannelID := uint16(1)
+	inputChannel, err := peerConnection.CreateDataChannel("input", &webrtc.DataChannelInit{
+		ID: &inputChannelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	inputChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		brv.mu.Lock()
+		handler := brv.inputHandler
+		brv.mu.Unlock()
+		if handler == nil {
+			return
+		}
+		if err := brv.dispatchInputEvent(msg.Data); err != nil {
+			brv.logger.Debugw("error dispatching input event", "error", err)
+		}
+	})
+	return inputChannel, nil
+}
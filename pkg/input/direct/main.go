@@ -1,23 +1,38 @@
+// Package direct implements input.EventType dispatch by handing each event to
+// per-OS functions, for RemoteView deployments running on the same machine
+// the user is viewing. The non-darwin build (other.go) prints each event to
+// stdout instead of injecting it.
 package direct
 
 import (
-	"encoding/json"
-
 	"github.com/trevor403/gostream/pkg/input"
 )
 
-func Handle(data []byte) {
-	raw := input.RawEvent{}
-	_ = json.Unmarshal(data, &raw)
-
-	switch raw.Type {
-	case input.KeyEventType:
-		ev := input.KeyEvent{}
-		json.Unmarshal(data, &ev)
-		HandleKey(ev)
-	case input.MouseEventType:
-		ev := input.MouseEvent{}
-		json.Unmarshal(data, &ev)
-		HandlePtr(ev)
-	}
+// Handler is this package's gostream.InputHandler implementation, forwarding
+// each event to this package's OS-specific functions. A RemoteView uses it
+// only once it is passed to SetInputHandler.
+type Handler struct{}
+
+func (Handler) HandleKey(ev input.KeyEvent) error {
+	return HandleKey(ev)
+}
+
+func (Handler) HandleMouse(ev input.MouseEvent) error {
+	return HandlePtr(ev)
+}
+
+func (Handler) HandleWheel(ev input.WheelEvent) error {
+	return HandleWheel(ev)
+}
+
+func (Handler) HandleTouch(ev input.TouchEvent) error {
+	return HandleTouch(ev)
+}
+
+func (Handler) HandleGamepad(ev input.GamepadEvent) error {
+	return HandleGamepad(ev)
+}
+
+func (Handler) HandleClipboard(ev input.ClipboardEvent) error {
+	return HandleClipboard(ev)
 }
@@ -17,3 +17,23 @@ func HandleKey(ev input.KeyEvent) error {
 	fmt.Println("sending key", ev)
 	return nil
 }
+
+func HandleWheel(ev input.WheelEvent) error {
+	fmt.Println("sending wheel", ev)
+	return nil
+}
+
+func HandleTouch(ev input.TouchEvent) error {
+	fmt.Println("sending touch", ev)
+	return nil
+}
+
+func HandleGamepad(ev input.GamepadEvent) error {
+	fmt.Println("sending gamepad", ev)
+	return nil
+}
+
+func HandleClipboard(ev input.ClipboardEvent) error {
+	fmt.Println("sending clipboard", ev)
+	return nil
+}
@@ -0,0 +1,101 @@
+// Package input defines the wire schema for the InputChannel data channel:
+// structured mouse, keyboard, touch, gamepad, and clipboard events sent from
+// a connecting browser to a RemoteView, and decoded there for dispatch to an
+// InputHandler.
+package input
+
+// Schema is the current InputChannel JSON schema version, sent as
+// RawEvent.Version so a handler can reject events from a frontend built
+// against an incompatible version instead of silently misinterpreting them.
+const Schema = 1
+
+// EventType identifies which concrete event a RawEvent's JSON decodes into.
+type EventType string
+
+const (
+	KeyEventType       EventType = "key"
+	MouseEventType     EventType = "mouse"
+	WheelEventType     EventType = "wheel"
+	TouchEventType     EventType = "touch"
+	GamepadEventType   EventType = "gamepad"
+	ClipboardEventType EventType = "clipboard"
+)
+
+// RawEvent is the envelope every InputChannel message decodes into first, so
+// its Type can select which concrete event type to decode the same bytes
+// into next.
+type RawEvent struct {
+	Version int       `json:"version"`
+	Type    EventType `json:"type"`
+}
+
+// Modifier is a bitmask of held modifier keys, carried on KeyEvent and
+// MouseEvent so a handler doesn't need a separate keyboard-state side
+// channel to interpret e.g. ctrl-click or shift-drag.
+type Modifier uint8
+
+const (
+	ModShift Modifier = 1 << iota
+	ModControl
+	ModAlt
+	ModMeta
+)
+
+// KeyEvent is a key down or key up.
+type KeyEvent struct {
+	RawEvent
+	Down      bool     `json:"down"`
+	Key       string   `json:"key"`
+	Code      string   `json:"code"`
+	Modifiers Modifier `json:"modifiers"`
+}
+
+// MouseEvent is a pointer move, button down, or button up. Move events carry
+// Down=false and Button=-1.
+type MouseEvent struct {
+	RawEvent
+	Down      bool     `json:"down"`
+	X         int      `json:"x"`
+	Y         int      `json:"y"`
+	Button    int      `json:"button"`
+	Modifiers Modifier `json:"modifiers"`
+}
+
+// WheelEvent is a scroll/trackpad gesture, reported as pixel deltas.
+type WheelEvent struct {
+	RawEvent
+	X      int     `json:"x"`
+	Y      int     `json:"y"`
+	DeltaX float64 `json:"deltaX"`
+	DeltaY float64 `json:"deltaY"`
+}
+
+// TouchPoint is one finger within a TouchEvent.
+type TouchPoint struct {
+	ID int `json:"id"`
+	X  int `json:"x"`
+	Y  int `json:"y"`
+}
+
+// TouchEvent reports the full set of active touch points for one phase of a
+// touch gesture.
+type TouchEvent struct {
+	RawEvent
+	Phase  string       `json:"phase"` // "start", "move", "end", or "cancel"
+	Points []TouchPoint `json:"points"`
+}
+
+// GamepadEvent reports one connected gamepad's full button and axis state,
+// sent on a polling interval by the frontend rather than per-change.
+type GamepadEvent struct {
+	RawEvent
+	Index   int       `json:"index"`
+	Buttons []float64 `json:"buttons"`
+	Axes    []float64 `json:"axes"`
+}
+
+// ClipboardEvent syncs the browser's clipboard text to the remote side.
+type ClipboardEvent struct {
+	RawEvent
+	Text string `json:"text"`
+}
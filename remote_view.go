@@ -9,13 +9,16 @@ import (
 	"image"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/edaniels/golog"
 
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
 )
@@ -24,7 +27,13 @@ type RemoteView interface {
 	Stop()
 	Ready() <-chan struct{}
 	InputFrames() chan<- image.Image // TODO(erd): does duration of frame matter?
-	SetOnClickHandler(func(x, y int))
+	// OutputFrames yields decoded frames received from a connecting peer's
+	// inbound video track, when RemoteViewConfig.Depacketizers is configured.
+	OutputFrames() <-chan image.Image
+	// SetInputHandler registers the handler that receives structured mouse,
+	// keyboard, touch, gamepad, and clipboard events sent over the
+	// InputChannel data channel, replacing any handler set previously.
+	SetInputHandler(InputHandler)
 	SetOnDataHandler(func(data []byte))
 	SendData(data []byte)
 	SendText(msg string)
@@ -32,7 +41,29 @@ type RemoteView interface {
 	HTML() RemoteViewHTML
 	SinglePageHTML() string
 	Handler() RemoteViewHandler
+	// WebSocketHandler serves the same offer/answer exchange as Handler but over a
+	// long-lived WebSocket connection, trickling ICE candidates as they're gathered
+	// instead of blocking on GatheringCompletePromise.
+	WebSocketHandler() RemoteViewHandler
+	// RestartICE renegotiates every remote client connected over the WebSocket
+	// signaling path by sending a fresh offer with ICERestart set.
+	RestartICE() error
+	// WHIPHandler accepts WHIP (WebRTC-HTTP Ingestion Protocol) offers so external
+	// tools like OBS or GStreamer's whipclientsink can publish into this stream.
+	WHIPHandler() RemoteViewHandler
+	// WHEPHandler accepts WHEP (WebRTC-HTTP Egress Protocol) offers so WHEP-compatible
+	// players can subscribe to this stream without the POST/base64 exchange Handler uses.
+	WHEPHandler() RemoteViewHandler
+	// SetBitrateLayers configures the quality layers clients adaptively switch
+	// between as their estimated downlink bitrate changes. See BitrateLayer.
+	SetBitrateLayers(layers []BitrateLayer) error
 	CommandRegistry() CommandRegistry
+	// Stats returns the most recent per-remote-client metrics snapshot,
+	// refreshed on a background interval. See RemoteClientStats.
+	Stats() map[string]RemoteClientStats
+	// MetricsHandler serves the stats from Stats in Prometheus text exposition
+	// format, labeled by stream, candidate type, and codec.
+	MetricsHandler() RemoteViewHandler
 }
 
 type RemoteViewHTML struct {
@@ -45,21 +76,28 @@ func NewRemoteView(config RemoteViewConfig) (RemoteView, error) {
 	if logger == nil {
 		logger = golog.Global
 	}
-	if config.EncoderFactory == nil {
-		return nil, errors.New("no encoder factory set")
+	if len(config.EncoderFactories) == 0 {
+		return nil, errors.New("no encoder factories set")
 	}
 	ctx, cancelFunc := context.WithCancel(context.Background())
-	return &basicRemoteView{
+	brv := &basicRemoteView{
 		config:             config,
 		readyCh:            make(chan struct{}),
 		inputFrames:        make(chan image.Image),
-		outputFrames:       make(chan []byte),
+		outputFrames:       make(chan encodedFrame),
+		recvFrames:         make(chan image.Image),
 		peerToRemoteClient: map[*webrtc.PeerConnection]remoteClient{},
+		encoders:           map[EncoderFactory]Encoder{},
 		commandRegistry:    NewCommandRegistry(),
+		whipSessions:       map[string]*webrtc.PeerConnection{},
+		whepSessions:       map[string]*webrtc.PeerConnection{},
 		logger:             logger,
 		shutdownCtx:        ctx,
 		shutdownCtxCancel:  cancelFunc,
-	}, nil
+	}
+	brv.backgroundProcessing.Add(1)
+	go brv.watchStats()
+	return brv, nil
 }
 
 type basicRemoteView struct {
@@ -69,15 +107,22 @@ type basicRemoteView struct {
 	readyCh              chan struct{}
 	peerToRemoteClient   map[*webrtc.PeerConnection]remoteClient
 	inputFrames          chan image.Image
-	outputFrames         chan []byte
-	encoder              Encoder
+	outputFrames         chan encodedFrame
+	recvFrames           chan image.Image
+	encoders             map[EncoderFactory]Encoder
 	onDataHandler        func(data []byte)
-	onClickHandler       func(x, y int)
+	inputHandler         InputHandler
+	resourceMu           sync.Mutex
+	whipSessions         map[string]*webrtc.PeerConnection
+	whepSessions         map[string]*webrtc.PeerConnection
+	bitrateTracks        []bitrateTrack
+	layerEncoders        map[string]Encoder
 	commandRegistry      CommandRegistry
 	shutdownCtx          context.Context
 	shutdownCtxCancel    func()
 	backgroundProcessing sync.WaitGroup
 	logger               golog.Logger
+	statsSnapshot        atomic.Value // map[string]RemoteClientStats
 }
 
 type RemoteViewHandler struct {
@@ -131,129 +176,26 @@ func (brv *basicRemoteView) Handler() RemoteViewHandler {
 		offer := webrtc.SessionDescription{}
 		Decode(in, &offer)
 
-		m := webrtc.MediaEngine{}
-		if err := m.RegisterDefaultCodecs(); err != nil {
-			panic(err)
-		}
-		options := []func(a *webrtc.API){webrtc.WithMediaEngine(&m)}
-		if brv.config.Debug {
-			options = append(options, webrtc.WithSettingEngine(webrtc.SettingEngine{
-				LoggerFactory: webrtcLoggerFactory{brv.logger},
-			}))
-		}
-		webAPI := webrtc.NewAPI(options...)
-
-		// Create a new RTCPeerConnection
-		peerConnection, err := webAPI.NewPeerConnection(brv.config.WebRTCConfig)
+		encoderFactory, err := selectEncoderFactory(offer.SDP, brv.config.EncoderFactories)
 		if err != nil {
-			panic(err)
-		}
-
-		iceConnectedCtx, iceConnectedCtxCancel := context.WithCancel(context.TODO())
-
-		// Set the handler for ICE connection state
-		// This will notify you when the peer has connected/disconnected
-		peerConnection.OnICEConnectionStateChange(func(connectionState webrtc.ICEConnectionState) {
-			connInfo := getPeerConnectionStats(peerConnection)
-			brv.logger.Debugw("connection state changed",
-				"conn_id", connInfo.ID,
-				"conn_state", connectionState.String(),
-				"conn_remote_candidates", connInfo.RemoteCandidates,
-			)
-			if connectionState == webrtc.ICEConnectionStateConnected {
-				iceConnectedCtxCancel()
-				return
-			}
-			switch connectionState {
-			case webrtc.ICEConnectionStateDisconnected,
-				webrtc.ICEConnectionStateFailed,
-				webrtc.ICEConnectionStateClosed:
-				brv.removeRemoteClient(peerConnection)
+			w.WriteHeader(http.StatusBadRequest)
+			if _, err := w.Write([]byte(err.Error())); err != nil {
+				brv.logger.Error(err)
 			}
-		})
-
-		videoTrack, err := webrtc.NewTrackLocalStaticSample(
-			webrtc.RTPCodecCapability{MimeType: brv.config.EncoderFactory.MIMEType()},
-			"video",
-			"pion",
-		)
-		if err != nil {
-			panic(err)
-		}
-
-		if _, err := peerConnection.AddTrack(videoTrack); err != nil {
-			panic(err)
+			return
 		}
 
-		dataChannelID := uint16(0)
-		dataChannel, err := peerConnection.CreateDataChannel("data", &webrtc.DataChannelInit{
-			ID: &dataChannelID,
-		})
+		peerConnection, dataChannel, iceConnectedCtx, estimator, err := brv.newPeerConnection()
 		if err != nil {
 			panic(err)
 		}
-		dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
-			if brv.onDataHandler == nil {
-				if !msg.IsString {
-					return
-				}
-				cmd, err := UnmarshalCommand(string(msg.Data))
-				if err != nil {
-					brv.logger.Debugw("error unmarshaling command", "error", err)
-					if err := dataChannel.SendText(err.Error()); err != nil {
-						brv.logger.Error(err)
-					}
-					return
-				}
-				resp, err := brv.CommandRegistry().Process(cmd)
-				if err != nil {
-					brv.logger.Debugw("error processing command", "error", err)
-					if err := dataChannel.SendText(err.Error()); err != nil {
-						brv.logger.Error(err)
-					}
-					return
-				}
-				if resp == nil {
-					return
-				}
-				if resp.isText {
-					if err := dataChannel.SendText(string(resp.data)); err != nil {
-						brv.logger.Error(err)
-					}
-					return
-				}
-				if err := dataChannel.Send(resp.data); err != nil {
-					brv.logger.Error(err)
-				}
-			}
-			brv.onDataHandler(msg.Data)
-		})
 
-		clickChannelID := uint16(1)
-		clickChannel, err := peerConnection.CreateDataChannel("clicks", &webrtc.DataChannelInit{
-			ID: &clickChannelID,
-		})
+		videoTrack, sender, err := brv.addVideoTrack(peerConnection, encoderFactory)
 		if err != nil {
 			panic(err)
 		}
-		clickChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
-			if brv.onClickHandler == nil {
-				return
-			}
-			coords := strings.Split(string(msg.Data), ",")
-			if len(coords) != 2 {
-				panic(len(coords))
-			}
-			x, err := strconv.ParseFloat(coords[0], 32)
-			if err != nil {
-				panic(err)
-			}
-			y, err := strconv.ParseFloat(coords[1], 32)
-			if err != nil {
-				panic(err)
-			}
-			brv.onClickHandler(int(x), int(y)) // handler should return fast otherwise it could block
-		})
+		brv.watchRTCP(peerConnection, sender, encoderFactory)
+		brv.watchBitrateEstimate(peerConnection, estimator)
 
 		// Set the remote SessionDescription
 		if err := peerConnection.SetRemoteDescription(offer); err != nil {
@@ -289,6 +231,7 @@ func (brv *basicRemoteView) Handler() RemoteViewHandler {
 		// Block until ICE Gathering is complete, disabling trickle ICE
 		// we do this because we only can exchange one signaling message
 		// in a production application you should exchange ICE Candidates via OnICECandidate
+		// (see WebSocketHandler for a trickle ICE signaling path)
 		select {
 		case <-brv.shutdownCtx.Done():
 			return
@@ -310,7 +253,12 @@ func (brv *basicRemoteView) Handler() RemoteViewHandler {
 			case <-iceConnectedCtx.Done():
 			}
 
-			brv.addRemoteClient(peerConnection, remoteClient{dataChannel, videoTrack})
+			brv.addRemoteClient(peerConnection, remoteClient{
+				dataChannel:    dataChannel,
+				videoTrack:     videoTrack,
+				videoSender:    sender,
+				encoderFactory: encoderFactory,
+			})
 
 			brv.readyOnce.Do(func() {
 				close(brv.readyCh)
@@ -323,6 +271,164 @@ func (brv *basicRemoteView) Handler() RemoteViewHandler {
 	return RemoteViewHandler{handlerName, handlerFunc}
 }
 
+// newWebRTCAPI builds a pion webrtc.API configured with the default codecs, a
+// send-side bandwidth estimator fed by TWCC/REMB feedback, and, when debug
+// logging is enabled, a logger factory that routes through brv.logger. The
+// returned estimator is nil-safe to ignore for receive-only peer connections.
+func (brv *basicRemoteView) newWebRTCAPI() (*webrtc.API, cc.BandwidthEstimator, error) {
+	m := webrtc.MediaEngine{}
+	if err := m.RegisterDefaultCodecs(); err != nil {
+		return nil, nil, err
+	}
+
+	registry := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(&m, registry); err != nil {
+		return nil, nil, err
+	}
+
+	estimator, err := gcc.NewSendSideBWE(gcc.SendSideBWEInitialBitrate(1_000_000))
+	if err != nil {
+		return nil, nil, err
+	}
+	congestionController, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
+		return estimator, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	registry.Add(congestionController)
+
+	options := []func(a *webrtc.API){webrtc.WithMediaEngine(&m), webrtc.WithInterceptorRegistry(registry)}
+	if brv.config.Debug {
+		options = append(options, webrtc.WithSettingEngine(webrtc.SettingEngine{
+			LoggerFactory: webrtcLoggerFactory{brv.logger},
+		}))
+	}
+	return webrtc.NewAPI(options...), estimator, nil
+}
+
+// newPeerConnection creates a peer connection along with the data/input channels
+// shared by every signaling path (POST and WebSocket). The video track is added
+// separately via addVideoTrack once the negotiated codec is known. The returned
+// context is done once the peer connection reaches the connected ICE state, and
+// the returned estimator reports this connection's estimated downlink bitrate
+// for SetBitrateLayers-driven track switching.
+func (brv *basicRemoteView) newPeerConnection() (
+	*webrtc.PeerConnection, *webrtc.DataChannel, context.Context, cc.BandwidthEstimator, error,
+) {
+	webAPI, estimator, err := brv.newWebRTCAPI()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	peerConnection, err := webAPI.NewPeerConnection(brv.config.WebRTCConfig)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	if len(brv.config.Depacketizers) > 0 {
+		if _, err := peerConnection.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
+			Direction: webrtc.RTPTransceiverDirectionRecvonly,
+		}); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		brv.watchInboundTracks(peerConnection)
+	}
+
+	iceConnectedCtx, iceConnectedCtxCancel := context.WithCancel(context.TODO())
+
+	// Set the handler for ICE connection state
+	// This will notify you when the peer has connected/disconnected
+	peerConnection.OnICEConnectionStateChange(func(connectionState webrtc.ICEConnectionState) {
+		connInfo := getPeerConnectionStats(peerConnection)
+		brv.logger.Debugw("connection state changed",
+			"conn_id", connInfo.ID,
+			"conn_state", connectionState.String(),
+			"conn_remote_candidates", connInfo.RemoteCandidates,
+		)
+		if connectionState == webrtc.ICEConnectionStateConnected {
+			iceConnectedCtxCancel()
+			return
+		}
+		switch connectionState {
+		case webrtc.ICEConnectionStateDisconnected,
+			webrtc.ICEConnectionStateFailed,
+			webrtc.ICEConnectionStateClosed:
+			brv.removeRemoteClient(peerConnection)
+		}
+	})
+
+	dataChannelID := uint16(0)
+	dataChannel, err := peerConnection.CreateDataChannel("data", &webrtc.DataChannelInit{
+		ID: &dataChannelID,
+	})
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		if brv.onDataHandler == nil {
+			if !msg.IsString {
+				return
+			}
+			cmd, err := UnmarshalCommand(string(msg.Data))
+			if err != nil {
+				brv.logger.Debugw("error unmarshaling command", "error", err)
+				if err := dataChannel.SendText(err.Error()); err != nil {
+					brv.logger.Error(err)
+				}
+				return
+			}
+			resp, err := brv.CommandRegistry().Process(cmd)
+			if err != nil {
+				brv.logger.Debugw("error processing command", "error", err)
+				if err := dataChannel.SendText(err.Error()); err != nil {
+					brv.logger.Error(err)
+				}
+				return
+			}
+			if resp == nil {
+				return
+			}
+			if resp.isText {
+				if err := dataChannel.SendText(string(resp.data)); err != nil {
+					brv.logger.Error(err)
+				}
+				return
+			}
+			if err := dataChannel.Send(resp.data); err != nil {
+				brv.logger.Error(err)
+			}
+		}
+		brv.onDataHandler(msg.Data)
+	})
+
+	if _, err := brv.createInputChannel(peerConnection); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return peerConnection, dataChannel, iceConnectedCtx, estimator, nil
+}
+
+// addVideoTrack creates and attaches a video track advertising factory's MIME
+// type. It's called once the negotiated codec for a peer is known, since each
+// remoteClient may use a different factory. The returned RTPSender is used by
+// watchRTCP to drain inbound PLI/FIR/NACK feedback for that track.
+func (brv *basicRemoteView) addVideoTrack(peerConnection *webrtc.PeerConnection, factory EncoderFactory) (*webrtc.TrackLocalStaticSample, *webrtc.RTPSender, error) {
+	videoTrack, err := webrtc.NewTrackLocalStaticSample(
+		webrtc.RTPCodecCapability{MimeType: factory.MIMEType()},
+		"video",
+		"pion",
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	sender, err := peerConnection.AddTrack(videoTrack)
+	if err != nil {
+		return nil, nil, err
+	}
+	return videoTrack, sender, nil
+}
+
 type peerConnectionStats struct {
 	ID               string
 	RemoteCandidates map[string]string
@@ -431,14 +537,18 @@ func (brv *basicRemoteView) SetOnDataHandler(handler func(data []byte)) {
 	brv.onDataHandler = handler
 }
 
-func (brv *basicRemoteView) SetOnClickHandler(handler func(x, y int)) {
+func (brv *basicRemoteView) SetInputHandler(handler InputHandler) {
 	brv.mu.Lock()
 	defer brv.mu.Unlock()
-	brv.onClickHandler = handler
+	brv.inputHandler = handler
 }
 
 func (brv *basicRemoteView) SendData(data []byte) {
 	for _, rc := range brv.getRemoteClients() {
+		if rc.dataChannel == nil {
+			// WHEP clients (playback-only) have no data channel.
+			continue
+		}
 		if err := rc.dataChannel.Send(data); err != nil {
 			brv.logger.Error(err)
 		}
@@ -447,6 +557,10 @@ func (brv *basicRemoteView) SendData(data []byte) {
 
 func (brv *basicRemoteView) SendText(msg string) {
 	for _, rc := range brv.getRemoteClients() {
+		if rc.dataChannel == nil {
+			// WHEP clients (playback-only) have no data channel.
+			continue
+		}
 		if err := rc.dataChannel.SendText(msg); err != nil {
 			brv.logger.Error(err)
 		}
@@ -457,12 +571,22 @@ func (brv *basicRemoteView) InputFrames() chan<- image.Image {
 	return brv.inputFrames
 }
 
+func (brv *basicRemoteView) OutputFrames() <-chan image.Image {
+	return brv.recvFrames
+}
+
+// encodedFrame carries an encoded sample tagged with the factory that produced
+// it, so processOutputFrames can route it only to clients using that codec.
+type encodedFrame struct {
+	factory EncoderFactory
+	data    []byte
+}
+
 func (brv *basicRemoteView) processInputFrames() {
 	defer func() {
 		close(brv.outputFrames)
 		brv.backgroundProcessing.Done()
 	}()
-	firstFrame := true
 	for {
 		select {
 		case <-brv.shutdownCtx.Done():
@@ -478,23 +602,25 @@ func (brv *basicRemoteView) processInputFrames() {
 		if frame == nil {
 			continue
 		}
-		if firstFrame {
-			bounds := frame.Bounds()
-			if err := brv.initCodec(bounds.Dx(), bounds.Dy()); err != nil {
+
+		bounds := frame.Bounds()
+		for _, factory := range brv.activeEncoderFactories() {
+			encoder, err := brv.encoderFor(factory, bounds.Dx(), bounds.Dy())
+			if err != nil {
 				brv.logger.Error(err)
-				return
+				continue
+			}
+			data, err := encoder.Encode(frame)
+			if err != nil {
+				brv.logger.Error(err)
+				continue
+			}
+			if data != nil {
+				brv.outputFrames <- encodedFrame{factory, data}
 			}
-			firstFrame = false
 		}
 
-		encodedFrame, err := brv.encoder.Encode(frame)
-		if err != nil {
-			brv.logger.Error(err)
-			continue
-		}
-		if encodedFrame != nil {
-			brv.outputFrames <- encodedFrame
-		}
+		brv.fanOutToLayers(frame)
 	}
 }
 
@@ -509,7 +635,15 @@ func (brv *basicRemoteView) processOutputFrames() {
 		}
 		now := time.Now()
 		for _, rc := range brv.getRemoteClients() {
-			if ivfErr := rc.videoTrack.WriteSample(media.Sample{Data: outputFrame, Duration: 33 * time.Millisecond}); ivfErr != nil {
+			if rc.layer != nil {
+				// this client's track is fed by its assigned bitrate layer's
+				// own encoder goroutine instead (see bitrate.go processLayer)
+				continue
+			}
+			if rc.encoderFactory != outputFrame.factory {
+				continue
+			}
+			if ivfErr := rc.videoTrack.WriteSample(media.Sample{Data: outputFrame.data, Duration: 33 * time.Millisecond}); ivfErr != nil {
 				panic(ivfErr)
 			}
 		}
@@ -520,25 +654,68 @@ func (brv *basicRemoteView) processOutputFrames() {
 	}
 }
 
-func (brv *basicRemoteView) initCodec(width, height int) error {
-	if brv.encoder != nil {
-		return errors.New("already initialized codec")
+// activeEncoderFactories returns the distinct codecs currently in use by
+// connected remote clients, so processInputFrames only encodes what's needed.
+func (brv *basicRemoteView) activeEncoderFactories() []EncoderFactory {
+	seen := map[EncoderFactory]bool{}
+	var factories []EncoderFactory
+	for _, rc := range brv.getRemoteClients() {
+		if rc.layer != nil || rc.encoderFactory == nil || seen[rc.encoderFactory] {
+			continue
+		}
+		seen[rc.encoderFactory] = true
+		factories = append(factories, rc.encoderFactory)
 	}
+	return factories
+}
 
-	var err error
-	brv.encoder, err = brv.config.EncoderFactory.New(width, height, brv.logger)
-	return err
+// encoderFor returns the Encoder for factory, creating it on first use now that
+// frame dimensions are known.
+func (brv *basicRemoteView) encoderFor(factory EncoderFactory, width, height int) (Encoder, error) {
+	brv.mu.Lock()
+	defer brv.mu.Unlock()
+	if encoder, ok := brv.encoders[factory]; ok {
+		return encoder, nil
+	}
+	encoder, err := factory.New(width, height, brv.logger)
+	if err != nil {
+		return nil, err
+	}
+	brv.encoders[factory] = encoder
+	return encoder, nil
 }
 
 type remoteClient struct {
-	dataChannel *webrtc.DataChannel
-	videoTrack  *webrtc.TrackLocalStaticSample
+	dataChannel    *webrtc.DataChannel
+	videoTrack     *webrtc.TrackLocalStaticSample
+	videoSender    *webrtc.RTPSender
+	encoderFactory EncoderFactory
+	// layer is nil until watchBitrateEstimate first switches this client onto
+	// one of the SetBitrateLayers layers, and is swapped by it as the estimated
+	// downlink bitrate crosses thresholds.
+	layer *BitrateLayer
+	// signal is non-nil when this client was established over WebSocketHandler,
+	// and is used by RestartICE to trickle a renegotiation offer.
+	signal *wsSignalConn
 }
 
 func (brv *basicRemoteView) addRemoteClient(peerConnection *webrtc.PeerConnection, rc remoteClient) {
+	brv.updateRemoteClient(peerConnection, rc)
+	if rc.encoderFactory != nil {
+		// give the new viewer a keyframe immediately instead of waiting out the
+		// rest of the encoder's natural keyframe interval
+		brv.forceKeyFrame(rc.encoderFactory)
+	}
+}
+
+// updateRemoteClient stores rc without forcing a keyframe, unlike
+// addRemoteClient. Callers that are revising an existing client's record
+// (e.g. watchBitrateEstimate switching its layer) want to decide for
+// themselves which encoder's keyframe, if any, the change calls for.
+func (brv *basicRemoteView) updateRemoteClient(peerConnection *webrtc.PeerConnection, rc remoteClient) {
 	brv.mu.Lock()
-	defer brv.mu.Unlock()
 	brv.peerToRemoteClient[peerConnection] = rc
+	brv.mu.Unlock()
 }
 
 func (brv *basicRemoteView) removeRemoteClient(peerConnection *webrtc.PeerConnection) {
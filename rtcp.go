@@ -0,0 +1,97 @@
+package gostream
+
+import (
+	"io"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+// rtcpPLIInterval is how often watchRTCP requests a keyframe even absent any
+// receiver feedback, matching the cadence neko's manager uses to keep late
+// joiners from staring at a gray screen for seconds while waiting for a
+// natural IDR.
+const rtcpPLIInterval = 3 * time.Second
+
+// watchRTCP drains inbound RTCP on sender for the lifetime of brv, requesting an
+// immediate keyframe from peerConnection's current encoder whenever a receiver
+// reports a picture loss (PLI/FIR) or a NACK, in addition to the periodic
+// rtcpPLIInterval request. factory is the codec negotiated at connection time;
+// once watchBitrateEstimate switches peerConnection onto a bitrate layer,
+// forceKeyFrameForClient follows that switch instead of continuing to target
+// factory's now-unused encoder.
+func (brv *basicRemoteView) watchRTCP(peerConnection *webrtc.PeerConnection, sender *webrtc.RTPSender, factory EncoderFactory) {
+	brv.backgroundProcessing.Add(1)
+	go func() {
+		defer brv.backgroundProcessing.Done()
+
+		ticker := time.NewTicker(rtcpPLIInterval)
+		defer ticker.Stop()
+
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			buf := make([]byte, 1500)
+			for {
+				n, _, err := sender.Read(buf)
+				if err != nil {
+					if err != io.EOF {
+						brv.logger.Debugw("rtcp read error", "error", err)
+					}
+					return
+				}
+				packets, err := rtcp.Unmarshal(buf[:n])
+				if err != nil {
+					continue
+				}
+				for _, packet := range packets {
+					switch packet.(type) {
+					case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest, *rtcp.TransportLayerNack:
+						brv.forceKeyFrameForClient(peerConnection, factory)
+					}
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-brv.shutdownCtx.Done():
+				return
+			case <-readDone:
+				return
+			case <-ticker.C:
+				brv.forceKeyFrameForClient(peerConnection, factory)
+			}
+		}
+	}()
+}
+
+// forceKeyFrame requests a keyframe from the shared encoder behind factory, if
+// one has been created yet.
+func (brv *basicRemoteView) forceKeyFrame(factory EncoderFactory) {
+	brv.mu.Lock()
+	encoder, ok := brv.encoders[factory]
+	brv.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err := encoder.ForceKeyFrame(); err != nil {
+		brv.logger.Error(err)
+	}
+}
+
+// forceKeyFrameForClient requests a keyframe from whichever encoder is
+// actually producing peerConnection's video right now: its bitrate layer's
+// encoder if watchBitrateEstimate has switched it onto one, or factory's
+// shared encoder otherwise.
+func (brv *basicRemoteView) forceKeyFrameForClient(peerConnection *webrtc.PeerConnection, factory EncoderFactory) {
+	brv.mu.Lock()
+	rc, ok := brv.peerToRemoteClient[peerConnection]
+	brv.mu.Unlock()
+	if ok && rc.layer != nil {
+		brv.forceKeyFrameForLayer(rc.layer.Name)
+		return
+	}
+	brv.forceKeyFrame(factory)
+}
@@ -0,0 +1,45 @@
+package gostream
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+)
+
+// selectEncoderFactory parses the offer's video media descriptions and returns
+// the first of factories whose MIMEType is also offered, following the
+// codec-per-format negotiation mediamtx's webrtcFindVideoTrack uses: the browser
+// advertises what it can decode and the server picks the first match in its own
+// preference order.
+func selectEncoderFactory(offerSDP string, factories []EncoderFactory) (EncoderFactory, error) {
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(offerSDP)); err != nil {
+		return nil, err
+	}
+
+	offeredMIMETypes := map[string]bool{}
+	for _, media := range parsed.MediaDescriptions {
+		if media.MediaName.Media != "video" {
+			continue
+		}
+		for _, attr := range media.Attributes {
+			if attr.Key != "rtpmap" {
+				continue
+			}
+			fields := strings.SplitN(attr.Value, " ", 2)
+			if len(fields) != 2 {
+				continue
+			}
+			codec := strings.SplitN(fields[1], "/", 2)[0]
+			offeredMIMETypes[strings.ToLower("video/"+codec)] = true
+		}
+	}
+
+	for _, factory := range factories {
+		if offeredMIMETypes[strings.ToLower(factory.MIMEType())] {
+			return factory, nil
+		}
+	}
+	return nil, errors.New("no mutually supported video codec found in offer")
+}
@@ -0,0 +1,205 @@
+package gostream
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
+)
+
+// wsUpgrader is shared across streams; origin checking is left to the caller's
+// own HTTP middleware, matching the permissive CORS headers Handler() sets.
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsSignalMessage is the JSON envelope exchanged over WebSocketHandler's socket.
+// event is one of "offer", "answer", or "candidate".
+type wsSignalMessage struct {
+	Event     string                   `json:"event"`
+	SDP       string                   `json:"sdp,omitempty"`
+	Candidate *webrtc.ICECandidateInit `json:"candidate,omitempty"`
+}
+
+// wsSignalConn wraps a websocket.Conn with a write mutex since pion's OnICECandidate
+// callback and RestartICE can both write concurrently with the read loop.
+type wsSignalConn struct {
+	mu             sync.Mutex
+	conn           *websocket.Conn
+	peerConnection *webrtc.PeerConnection
+}
+
+func (s *wsSignalConn) send(msg wsSignalMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteJSON(msg)
+}
+
+// WebSocketHandler serves the offer/answer/candidate exchange described in
+// wsSignalMessage over a WebSocket instead of the single POST exchange Handler
+// uses, trickling ICE candidates as pion gathers them so the connection doesn't
+// wait on GatheringCompletePromise.
+func (brv *basicRemoteView) WebSocketHandler() RemoteViewHandler {
+	handlerName := fmt.Sprintf("ws_%d", brv.streamNum())
+	handlerFunc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			brv.logger.Error(err)
+			return
+		}
+
+		var msg wsSignalMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			brv.logger.Error(err)
+			conn.Close()
+			return
+		}
+		if msg.Event != "offer" {
+			brv.logger.Errorw("expected offer as first signaling message", "event", msg.Event)
+			conn.Close()
+			return
+		}
+
+		encoderFactory, err := selectEncoderFactory(msg.SDP, brv.config.EncoderFactories)
+		if err != nil {
+			brv.logger.Error(err)
+			conn.Close()
+			return
+		}
+
+		peerConnection, dataChannel, iceConnectedCtx, estimator, err := brv.newPeerConnection()
+		if err != nil {
+			brv.logger.Error(err)
+			conn.Close()
+			return
+		}
+
+		videoTrack, sender, err := brv.addVideoTrack(peerConnection, encoderFactory)
+		if err != nil {
+			brv.logger.Error(err)
+			conn.Close()
+			return
+		}
+		brv.watchRTCP(peerConnection, sender, encoderFactory)
+		brv.watchBitrateEstimate(peerConnection, estimator)
+
+		signal := &wsSignalConn{conn: conn, peerConnection: peerConnection}
+
+		peerConnection.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+			if candidate == nil {
+				return
+			}
+			init := candidate.ToJSON()
+			if err := signal.send(wsSignalMessage{Event: "candidate", Candidate: &init}); err != nil {
+				brv.logger.Error(err)
+			}
+		})
+
+		if err := peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeOffer,
+			SDP:  msg.SDP,
+		}); err != nil {
+			brv.logger.Error(err)
+			conn.Close()
+			return
+		}
+
+		answer, err := peerConnection.CreateAnswer(nil)
+		if err != nil {
+			brv.logger.Error(err)
+			conn.Close()
+			return
+		}
+		if err := peerConnection.SetLocalDescription(answer); err != nil {
+			brv.logger.Error(err)
+			conn.Close()
+			return
+		}
+		if err := signal.send(wsSignalMessage{Event: "answer", SDP: answer.SDP}); err != nil {
+			brv.logger.Error(err)
+		}
+
+		brv.backgroundProcessing.Add(1)
+		go func() {
+			defer brv.backgroundProcessing.Done()
+			select {
+			case <-brv.shutdownCtx.Done():
+				return
+			case <-iceConnectedCtx.Done():
+			}
+
+			brv.addRemoteClient(peerConnection, remoteClient{
+				dataChannel:    dataChannel,
+				videoTrack:     videoTrack,
+				videoSender:    sender,
+				encoderFactory: encoderFactory,
+				signal:         signal,
+			})
+
+			brv.readyOnce.Do(func() {
+				close(brv.readyCh)
+				brv.backgroundProcessing.Add(2)
+				go brv.processInputFrames()
+				go brv.processOutputFrames()
+			})
+		}()
+
+		brv.backgroundProcessing.Add(1)
+		go func() {
+			defer brv.backgroundProcessing.Done()
+			defer conn.Close()
+			defer brv.removeRemoteClient(peerConnection)
+			for {
+				var in wsSignalMessage
+				if err := conn.ReadJSON(&in); err != nil {
+					return
+				}
+				switch in.Event {
+				case "candidate":
+					if in.Candidate == nil {
+						continue
+					}
+					if err := peerConnection.AddICECandidate(*in.Candidate); err != nil {
+						brv.logger.Error(err)
+					}
+				case "answer":
+					// sent back in response to a RestartICE-triggered offer
+					if err := peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+						Type: webrtc.SDPTypeAnswer,
+						SDP:  in.SDP,
+					}); err != nil {
+						brv.logger.Error(err)
+					}
+				default:
+					brv.logger.Debugw("unhandled signaling event", "event", in.Event)
+				}
+			}
+		}()
+	})
+	return RemoteViewHandler{handlerName, handlerFunc}
+}
+
+// RestartICE renegotiates every remote client established over WebSocketHandler by
+// creating a fresh offer with ICERestart set and trickling it through that client's
+// signaling socket, following the same pattern neko's peer.go uses to recover a
+// connection without tearing down the whole session.
+func (brv *basicRemoteView) RestartICE() error {
+	for _, rc := range brv.getRemoteClients() {
+		if rc.signal == nil {
+			continue // established over the POST path; nothing to renegotiate through
+		}
+		offer, err := rc.signal.peerConnection.CreateOffer(&webrtc.OfferOptions{ICERestart: true})
+		if err != nil {
+			return err
+		}
+		if err := rc.signal.peerConnection.SetLocalDescription(offer); err != nil {
+			return err
+		}
+		if err := rc.signal.send(wsSignalMessage{Event: "offer", SDP: offer.SDP}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
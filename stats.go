@@ -0,0 +1,156 @@
+package gostream
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// statsPollInterval is how often watchStats refreshes the snapshot Stats and
+// MetricsHandler read.
+const statsPollInterval = 2 * time.Second
+
+// RemoteClientStats is one connected peer's metrics, refreshed periodically
+// from its peerConnection.GetStats().
+type RemoteClientStats struct {
+	CandidateType   string // "host", "srflx", "prflx", or "relay"
+	Codec           string
+	BytesSent       uint64
+	FramesEncoded   uint32
+	TotalEncodeTime time.Duration
+	RoundTripTime   time.Duration
+	FractionLost    float64
+}
+
+// Stats returns the most recent per-remote-client metrics snapshot, keyed by
+// the same connection ID used in connection-state debug logging.
+func (brv *basicRemoteView) Stats() map[string]RemoteClientStats {
+	snapshot, _ := brv.statsSnapshot.Load().(map[string]RemoteClientStats)
+	if snapshot == nil {
+		return map[string]RemoteClientStats{}
+	}
+	return snapshot
+}
+
+// watchStats runs for the lifetime of brv, periodically refreshing the
+// snapshot Stats and MetricsHandler read from every connected client's
+// GetStats().
+func (brv *basicRemoteView) watchStats() {
+	defer brv.backgroundProcessing.Done()
+	ticker := time.NewTicker(statsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-brv.shutdownCtx.Done():
+			return
+		case <-ticker.C:
+			brv.statsSnapshot.Store(brv.collectStats())
+		}
+	}
+}
+
+func (brv *basicRemoteView) collectStats() map[string]RemoteClientStats {
+	brv.mu.Lock()
+	peers := make(map[*webrtc.PeerConnection]remoteClient, len(brv.peerToRemoteClient))
+	for pc, rc := range brv.peerToRemoteClient {
+		peers[pc] = rc
+	}
+	brv.mu.Unlock()
+
+	snapshot := make(map[string]RemoteClientStats, len(peers))
+	for peerConnection, rc := range peers {
+		connID, clientStats := collectRemoteClientStats(peerConnection, rc)
+		if connID == "" {
+			continue
+		}
+		snapshot[connID] = clientStats
+	}
+	return snapshot
+}
+
+func collectRemoteClientStats(peerConnection *webrtc.PeerConnection, rc remoteClient) (string, RemoteClientStats) {
+	var connID string
+	clientStats := RemoteClientStats{Codec: remoteClientCodec(rc)}
+	for _, stat := range peerConnection.GetStats() {
+		switch s := stat.(type) {
+		case webrtc.PeerConnectionStats:
+			connID = s.ID
+		case webrtc.ICECandidateStats:
+			if s.Type != webrtc.StatsTypeRemoteCandidate {
+				continue
+			}
+			if candidateType := iceCandidateTypeLabel(s.CandidateType); candidateType != "" {
+				clientStats.CandidateType = candidateType
+			}
+		case webrtc.OutboundRTPStreamStats:
+			clientStats.BytesSent += s.BytesSent
+			clientStats.FramesEncoded += s.FramesEncoded
+			clientStats.TotalEncodeTime += time.Duration(s.TotalEncodeTime * float64(time.Second))
+		case webrtc.RemoteInboundRTPStreamStats:
+			clientStats.RoundTripTime = time.Duration(s.RoundTripTime * float64(time.Second))
+			clientStats.FractionLost = s.FractionLost
+		}
+	}
+	return connID, clientStats
+}
+
+func remoteClientCodec(rc remoteClient) string {
+	if rc.layer != nil {
+		return rc.layer.EncoderFactory.MIMEType()
+	}
+	if rc.encoderFactory != nil {
+		return rc.encoderFactory.MIMEType()
+	}
+	return ""
+}
+
+func iceCandidateTypeLabel(candidateType webrtc.ICECandidateType) string {
+	switch candidateType {
+	case webrtc.ICECandidateTypeHost:
+		return "host"
+	case webrtc.ICECandidateTypeSrflx:
+		return "srflx"
+	case webrtc.ICECandidateTypePrflx:
+		return "prflx"
+	case webrtc.ICECandidateTypeRelay:
+		return "relay"
+	default:
+		return ""
+	}
+}
+
+// MetricsHandler serves brv.Stats() in Prometheus text exposition format,
+// labeled by stream, candidate type, and codec.
+func (brv *basicRemoteView) MetricsHandler() RemoteViewHandler {
+	handlerName := fmt.Sprintf("metrics_%d", brv.streamNum())
+	handlerFunc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		stream := brv.config.StreamName
+		for _, metric := range []struct {
+			name string
+			help string
+		}{
+			{"gostream_bytes_sent_total", "Total bytes sent on the outbound video track."},
+			{"gostream_frames_encoded_total", "Total frames encoded for the outbound video track."},
+			{"gostream_encode_time_seconds_total", "Total time spent encoding frames."},
+			{"gostream_round_trip_time_seconds", "Most recently reported RTCP round trip time."},
+			{"gostream_fraction_lost", "Most recently reported fraction of packets lost."},
+		} {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name)
+		}
+		for connID, stats := range brv.Stats() {
+			labels := fmt.Sprintf(
+				`{stream=%q,conn=%q,candidate_type=%q,codec=%q}`,
+				stream, connID, stats.CandidateType, stats.Codec,
+			)
+			fmt.Fprintf(w, "gostream_bytes_sent_total%s %d\n", labels, stats.BytesSent)
+			fmt.Fprintf(w, "gostream_frames_encoded_total%s %d\n", labels, stats.FramesEncoded)
+			fmt.Fprintf(w, "gostream_encode_time_seconds_total%s %f\n", labels, stats.TotalEncodeTime.Seconds())
+			fmt.Fprintf(w, "gostream_round_trip_time_seconds%s %f\n", labels, stats.RoundTripTime.Seconds())
+			fmt.Fprintf(w, "gostream_fraction_lost%s %f\n", labels, stats.FractionLost)
+		}
+	})
+	return RemoteViewHandler{handlerName, handlerFunc}
+}
@@ -0,0 +1,396 @@
+package gostream
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// newResourceID returns a random hex identifier suitable for use in a WHIP/WHEP
+// resource URL.
+func newResourceID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// WHIPHandler implements a WHIP (draft-ietf-wish-whip) ingest endpoint: a
+// `Content-Type: application/sdp` POST containing an offer is answered with a
+// `201 Created` whose `Location` header points at a resource URL supporting
+// `DELETE` (teardown) and `PATCH` (trickle ICE).
+func (brv *basicRemoteView) WHIPHandler() RemoteViewHandler {
+	handlerName := fmt.Sprintf("whip_%d", brv.streamNum())
+	handlerFunc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "Location")
+
+		if resourceID, ok := resourceIDFromPath(r.URL.Path, handlerName); ok {
+			brv.handleWHIPResource(w, r, resourceID)
+			return
+		}
+
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/sdp" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		webAPI, _, err := brv.newWebRTCAPI()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		peerConnection, err := webAPI.NewPeerConnection(brv.config.WebRTCConfig)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if _, err := peerConnection.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
+			Direction: webrtc.RTPTransceiverDirectionRecvonly,
+		}); err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		brv.watchInboundTracks(peerConnection)
+
+		if err := peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeOffer,
+			SDP:  string(body),
+		}); err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		answer, err := peerConnection.CreateAnswer(nil)
+		if err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		gatherComplete := webrtc.GatheringCompletePromise(peerConnection)
+		if err := peerConnection.SetLocalDescription(answer); err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-brv.shutdownCtx.Done():
+			peerConnection.Close()
+			return
+		case <-gatherComplete:
+		}
+
+		resourceID, err := newResourceID()
+		if err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		brv.resourceMu.Lock()
+		brv.whipSessions[resourceID] = peerConnection
+		brv.resourceMu.Unlock()
+		peerConnection.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+			switch state {
+			case webrtc.ICEConnectionStateDisconnected, webrtc.ICEConnectionStateFailed, webrtc.ICEConnectionStateClosed:
+				brv.resourceMu.Lock()
+				removeSessionByPeerConnection(brv.whipSessions, peerConnection)
+				brv.resourceMu.Unlock()
+				brv.removeRemoteClient(peerConnection)
+			}
+		})
+
+		w.Header().Set("Content-Type", "application/sdp")
+		w.Header().Set("Location", fmt.Sprintf("/%s/resource/%s", handlerName, resourceID))
+		w.WriteHeader(http.StatusCreated)
+		if _, err := w.Write([]byte(peerConnection.LocalDescription().SDP)); err != nil {
+			brv.logger.Error(err)
+		}
+	})
+	return RemoteViewHandler{handlerName, handlerFunc}
+}
+
+// WHEPHandler implements a WHEP (draft-ietf-wish-whep) playback endpoint using the
+// same resource lifecycle as WHIPHandler but egressing this stream's video track
+// instead of receiving one.
+func (brv *basicRemoteView) WHEPHandler() RemoteViewHandler {
+	handlerName := fmt.Sprintf("whep_%d", brv.streamNum())
+	handlerFunc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "Location")
+
+		if resourceID, ok := resourceIDFromPath(r.URL.Path, handlerName); ok {
+			brv.handleWHEPResource(w, r, resourceID)
+			return
+		}
+
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/sdp" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		encoderFactory, err := selectEncoderFactory(string(body), brv.config.EncoderFactories)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// WHEP is playback/egress-only, so we build our own send-only peer
+		// connection here instead of reusing newPeerConnection: that helper also
+		// wires up the data/input channels and (when Depacketizers are configured
+		// for chunk0-6-style inbound ingest) a recvonly video transceiver, none of
+		// which a WHEP player asked for or should see in its answer.
+		webAPI, estimator, err := brv.newWebRTCAPI()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		peerConnection, err := webAPI.NewPeerConnection(brv.config.WebRTCConfig)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		iceConnectedCtx, iceConnectedCtxCancel := context.WithCancel(context.TODO())
+		peerConnection.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+			if state == webrtc.ICEConnectionStateConnected {
+				iceConnectedCtxCancel()
+				return
+			}
+			switch state {
+			case webrtc.ICEConnectionStateDisconnected, webrtc.ICEConnectionStateFailed, webrtc.ICEConnectionStateClosed:
+				brv.resourceMu.Lock()
+				removeSessionByPeerConnection(brv.whepSessions, peerConnection)
+				brv.resourceMu.Unlock()
+				brv.removeRemoteClient(peerConnection)
+			}
+		})
+
+		videoTrack, sender, err := brv.addVideoTrack(peerConnection, encoderFactory)
+		if err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		brv.watchRTCP(peerConnection, sender, encoderFactory)
+		brv.watchBitrateEstimate(peerConnection, estimator)
+
+		if err := peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeOffer,
+			SDP:  string(body),
+		}); err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		answer, err := peerConnection.CreateAnswer(nil)
+		if err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		gatherComplete := webrtc.GatheringCompletePromise(peerConnection)
+		if err := peerConnection.SetLocalDescription(answer); err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-brv.shutdownCtx.Done():
+			peerConnection.Close()
+			return
+		case <-gatherComplete:
+		}
+
+		resourceID, err := newResourceID()
+		if err != nil {
+			peerConnection.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		brv.resourceMu.Lock()
+		brv.whepSessions[resourceID] = peerConnection
+		brv.resourceMu.Unlock()
+
+		brv.backgroundProcessing.Add(1)
+		go func() {
+			defer brv.backgroundProcessing.Done()
+			select {
+			case <-brv.shutdownCtx.Done():
+				return
+			case <-iceConnectedCtx.Done():
+			}
+
+			brv.addRemoteClient(peerConnection, remoteClient{
+				videoTrack:     videoTrack,
+				videoSender:    sender,
+				encoderFactory: encoderFactory,
+			})
+
+			brv.readyOnce.Do(func() {
+				close(brv.readyCh)
+				brv.backgroundProcessing.Add(2)
+				go brv.processInputFrames()
+				go brv.processOutputFrames()
+			})
+		}()
+
+		w.Header().Set("Content-Type", "application/sdp")
+		w.Header().Set("Location", fmt.Sprintf("/%s/resource/%s", handlerName, resourceID))
+		w.WriteHeader(http.StatusCreated)
+		if _, err := w.Write([]byte(peerConnection.LocalDescription().SDP)); err != nil {
+			brv.logger.Error(err)
+		}
+	})
+	return RemoteViewHandler{handlerName, handlerFunc}
+}
+
+// resourceIDFromPath extracts a resource ID from a `/<handlerName>/resource/<id>`
+// path, reporting ok=false for the bare ingest/egress path used to create sessions.
+func resourceIDFromPath(urlPath, handlerName string) (string, bool) {
+	prefix := "/" + handlerName + "/resource/"
+	if !strings.HasPrefix(urlPath, prefix) {
+		return "", false
+	}
+	id := path.Base(urlPath)
+	if id == "" || id == "." || id == "/" {
+		return "", false
+	}
+	return id, true
+}
+
+// removeSessionByPeerConnection deletes peerConnection's entry from sessions,
+// if present. Callers must hold brv.resourceMu.
+func removeSessionByPeerConnection(sessions map[string]*webrtc.PeerConnection, peerConnection *webrtc.PeerConnection) {
+	for resourceID, pc := range sessions {
+		if pc == peerConnection {
+			delete(sessions, resourceID)
+			return
+		}
+	}
+}
+
+func (brv *basicRemoteView) handleWHIPResource(w http.ResponseWriter, r *http.Request, resourceID string) {
+	brv.resourceMu.Lock()
+	peerConnection, ok := brv.whipSessions[resourceID]
+	brv.resourceMu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Methods", "PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	case http.MethodDelete:
+		brv.resourceMu.Lock()
+		delete(brv.whipSessions, resourceID)
+		brv.resourceMu.Unlock()
+		brv.removeRemoteClient(peerConnection)
+		if err := peerConnection.Close(); err != nil {
+			brv.logger.Error(err)
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPatch:
+		brv.handleTrickleICEPatch(w, r, peerConnection)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (brv *basicRemoteView) handleWHEPResource(w http.ResponseWriter, r *http.Request, resourceID string) {
+	brv.resourceMu.Lock()
+	peerConnection, ok := brv.whepSessions[resourceID]
+	brv.resourceMu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Methods", "PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	case http.MethodDelete:
+		brv.resourceMu.Lock()
+		delete(brv.whepSessions, resourceID)
+		brv.resourceMu.Unlock()
+		brv.removeRemoteClient(peerConnection)
+		if err := peerConnection.Close(); err != nil {
+			brv.logger.Error(err)
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPatch:
+		brv.handleTrickleICEPatch(w, r, peerConnection)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTrickleICEPatch applies a trickle ICE PATCH body (an SDP media-level
+// a=candidate fragment, per draft-ietf-wish-whip section 4.2) to the resource's
+// peer connection.
+func (brv *basicRemoteView) handleTrickleICEPatch(w http.ResponseWriter, r *http.Request, peerConnection *webrtc.PeerConnection) {
+	if ct := r.Header.Get("Content-Type"); ct != "application/trickle-ice-sdpfrag" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "a=candidate:") {
+			continue
+		}
+		candidate := webrtc.ICECandidateInit{Candidate: strings.TrimPrefix(line, "a=")}
+		if err := peerConnection.AddICECandidate(candidate); err != nil {
+			brv.logger.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}